@@ -0,0 +1,216 @@
+package dtlstest
+
+import (
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// conn is a packet-oriented net.Conn backed by a buffered channel into its
+// peer. Each Write is delivered as exactly one Read, mirroring the framing
+// guarantees of a UDP socket (unlike net.Pipe, which is a byte stream).
+type conn struct {
+	peer *conn
+
+	readCh  chan []byte
+	writeCh chan []byte
+
+	lock     sync.Mutex
+	doneCh   chan struct{}
+	doneOnce sync.Once
+
+	readDeadline  pipeDeadline
+	writeDeadline pipeDeadline
+
+	chaosLock      sync.Mutex
+	rng            *rand.Rand
+	lossProb       float64
+	reorderProb    float64
+	dupProb        float64
+	pendingReorder []byte
+}
+
+// Option configures the synthetic network impairment applied to a Conn's
+// outbound packets. Options passed to Listener.Dial are applied to both
+// sides of the resulting pair.
+type Option func(*conn)
+
+// WithLoss drops a written packet with probability p (0 <= p <= 1),
+// simulating a lost UDP datagram so DTLS retransmission logic is
+// exercised.
+func WithLoss(p float64) Option {
+	return func(c *conn) { c.lossProb = p }
+}
+
+// WithReorder swaps a written packet with the packet immediately following
+// it with probability p (0 <= p <= 1).
+func WithReorder(p float64) Option {
+	return func(c *conn) { c.reorderProb = p }
+}
+
+// WithDuplication delivers a written packet a second time with probability
+// p (0 <= p <= 1).
+func WithDuplication(p float64) Option {
+	return func(c *conn) { c.dupProb = p }
+}
+
+func newPipe(queueLen int) (client, server *conn) {
+	ab := make(chan []byte, queueLen)
+	ba := make(chan []byte, queueLen)
+
+	client = &conn{
+		readCh:        ab,
+		writeCh:       ba,
+		doneCh:        make(chan struct{}),
+		readDeadline:  makePipeDeadline(),
+		writeDeadline: makePipeDeadline(),
+		rng:           rand.New(rand.NewSource(1)), //nolint:gosec
+	}
+	server = &conn{
+		readCh:        ba,
+		writeCh:       ab,
+		doneCh:        make(chan struct{}),
+		readDeadline:  makePipeDeadline(),
+		writeDeadline: makePipeDeadline(),
+		rng:           rand.New(rand.NewSource(2)), //nolint:gosec
+	}
+	client.peer = server
+	server.peer = client
+
+	return client, server
+}
+
+// Read
+func (c *conn) Read(p []byte) (int, error) {
+	select {
+	case buf := <-c.readCh:
+		return copy(p, buf), nil
+	case <-c.doneCh:
+		return 0, io.EOF
+	case <-c.peer.doneCh:
+		// Packets the peer queued before closing are still delivered.
+		select {
+		case buf := <-c.readCh:
+			return copy(p, buf), nil
+		default:
+		}
+		return 0, io.EOF
+	case <-c.readDeadline.wait():
+		return 0, errTimeout
+	}
+}
+
+// Write writes p as a single packet to the peer Conn, subject to any
+// configured loss/reorder/duplication Options.
+func (c *conn) Write(p []byte) (int, error) {
+	select {
+	case <-c.doneCh:
+		return 0, io.EOF
+	case <-c.writeDeadline.wait():
+		return 0, errTimeout
+	default:
+	}
+
+	buf := append([]byte(nil), p...)
+
+	c.chaosLock.Lock()
+	defer c.chaosLock.Unlock()
+
+	if c.lossProb > 0 && c.rng.Float64() < c.lossProb {
+		return len(p), nil
+	}
+
+	if pending := c.pendingReorder; pending != nil {
+		c.pendingReorder = nil
+		if err := c.send(buf); err != nil {
+			return 0, err
+		}
+		_ = c.send(pending) // best effort; peer may have closed in between
+		return len(p), nil
+	}
+
+	if c.reorderProb > 0 && c.rng.Float64() < c.reorderProb {
+		c.pendingReorder = buf
+		return len(p), nil
+	}
+
+	if err := c.send(buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// send delivers buf to the peer's read queue, optionally duplicating it,
+// and reports io.EOF if either side has since closed or errTimeout if the
+// write deadline elapses while waiting for room in the peer's queue.
+func (c *conn) send(buf []byte) error {
+	select {
+	case c.writeCh <- buf:
+	case <-c.doneCh:
+		return io.EOF
+	case <-c.peer.doneCh:
+		return io.EOF
+	case <-c.writeDeadline.wait():
+		return errTimeout
+	}
+
+	if c.dupProb > 0 && c.rng.Float64() < c.dupProb {
+		select {
+		case c.writeCh <- append([]byte(nil), buf...):
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Close closes the Conn; the peer's Read calls observe io.EOF once its queue
+// is empty. Any packet still held back by a WithReorder delay is sent to the
+// peer first if the peer's queue has room for it, so a reorder that was
+// pending when the Conn closed degrades into an in-order delivery rather
+// than a silent drop.
+func (c *conn) Close() error {
+	c.doneOnce.Do(func() {
+		c.chaosLock.Lock()
+		pending := c.pendingReorder
+		c.pendingReorder = nil
+		c.chaosLock.Unlock()
+
+		if pending != nil {
+			select {
+			case c.writeCh <- pending:
+			default:
+			}
+		}
+
+		close(c.doneCh)
+	})
+	return nil
+}
+
+// LocalAddr returns the Conn's address.
+func (c *conn) LocalAddr() net.Addr { return pipeAddr{} }
+
+// RemoteAddr returns the peer's address.
+func (c *conn) RemoteAddr() net.Addr { return pipeAddr{} }
+
+// SetDeadline sets the read and write deadlines associated with the Conn.
+func (c *conn) SetDeadline(t time.Time) error {
+	c.readDeadline.set(t)
+	c.writeDeadline.set(t)
+	return nil
+}
+
+// SetReadDeadline sets the deadline for future Read calls.
+func (c *conn) SetReadDeadline(t time.Time) error {
+	c.readDeadline.set(t)
+	return nil
+}
+
+// SetWriteDeadline sets the deadline for future Write calls.
+func (c *conn) SetWriteDeadline(t time.Time) error {
+	c.writeDeadline.set(t)
+	return nil
+}
@@ -0,0 +1,75 @@
+package dtlstest
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// errTimeout is returned from Read/Write when the associated deadline has
+// elapsed. It is os.ErrDeadlineExceeded, which already satisfies net.Error
+// with Timeout() == true, so callers can use errors.Is(err,
+// os.ErrDeadlineExceeded) the same way they would against a real
+// net.Conn's deadline error.
+var errTimeout = os.ErrDeadlineExceeded
+
+// pipeDeadline mirrors the primitive used by udp.Conn: a mutex-guarded timer
+// with a channel that is closed once the deadline elapses and re-armed on
+// the next call to set.
+type pipeDeadline struct {
+	mu     sync.Mutex
+	timer  *time.Timer
+	cancel chan struct{}
+}
+
+func makePipeDeadline() pipeDeadline {
+	return pipeDeadline{cancel: make(chan struct{})}
+}
+
+func (d *pipeDeadline) set(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.timer != nil && !d.timer.Stop() {
+		<-d.cancel
+	}
+	d.timer = nil
+
+	closed := isClosedChan(d.cancel)
+
+	if t.IsZero() {
+		if closed {
+			d.cancel = make(chan struct{})
+		}
+		return
+	}
+
+	if dur := time.Until(t); dur > 0 {
+		if closed {
+			d.cancel = make(chan struct{})
+		}
+		d.timer = time.AfterFunc(dur, func() {
+			close(d.cancel)
+		})
+		return
+	}
+
+	if !closed {
+		close(d.cancel)
+	}
+}
+
+func (d *pipeDeadline) wait() chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.cancel
+}
+
+func isClosedChan(c chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,86 @@
+// Package dtlstest provides an in-memory net.Listener/net.Conn pair for
+// exercising DTLS handshake and record-layer code without binding a real
+// UDP socket. It is the packet-oriented analogue of grpc's bufconn: a
+// Listener hands out Conns that are connected to each other directly
+// through buffered channels, so tests run deterministically and don't pay
+// for (or flake on) OS scheduling or socket binding.
+package dtlstest
+
+import (
+	"errors"
+	"net"
+	"sync"
+)
+
+const defaultQueueLen = 128
+
+var errClosedListener = errors.New("dtlstest: listener closed")
+
+// Listener is an in-memory stand-in for udp.Listener.
+type Listener struct {
+	queueLen int
+
+	acceptCh chan *conn
+	doneCh   chan struct{}
+	doneOnce sync.Once
+}
+
+// Listen creates a Listener. sz is the number of packets buffered in each
+// direction of every Conn pair it produces; once that buffer is full, Write
+// blocks until the peer reads, either side closes, or the write deadline
+// elapses. It defaults to 128 if <= 0.
+func Listen(sz int) *Listener {
+	if sz <= 0 {
+		sz = defaultQueueLen
+	}
+	return &Listener{
+		queueLen: sz,
+		acceptCh: make(chan *conn),
+		doneCh:   make(chan struct{}),
+	}
+}
+
+// Accept waits for and returns the server side of the next Dialed Conn.
+func (l *Listener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.acceptCh:
+		return c, nil
+	case <-l.doneCh:
+		return nil, errClosedListener
+	}
+}
+
+// Close unblocks any pending Accept and Dial calls.
+func (l *Listener) Close() error {
+	l.doneOnce.Do(func() { close(l.doneCh) })
+	return nil
+}
+
+// Addr returns the listener's address.
+func (l *Listener) Addr() net.Addr {
+	return pipeAddr{}
+}
+
+// Dial creates a connected pair of Conns, hands the server side to a
+// pending or future Accept call, and returns the client side. The given
+// Option values (WithLoss, WithReorder, WithDuplication) are applied to
+// both the client and the server side, impairing the link in each direction.
+func (l *Listener) Dial(opts ...Option) (net.Conn, error) {
+	client, server := newPipe(l.queueLen)
+	for _, opt := range opts {
+		opt(client)
+		opt(server)
+	}
+
+	select {
+	case l.acceptCh <- server:
+		return client, nil
+	case <-l.doneCh:
+		return nil, errClosedListener
+	}
+}
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "memconn" }
+func (pipeAddr) String() string  { return "dtlstest.Listener" }
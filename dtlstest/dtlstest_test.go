@@ -0,0 +1,177 @@
+package dtlstest
+
+import (
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func dial(t *testing.T, opts ...Option) (client, server net.Conn) {
+	t.Helper()
+
+	l := Listen(4)
+	acceptCh := make(chan net.Conn, 1)
+	acceptErrCh := make(chan error, 1)
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			acceptErrCh <- err
+			return
+		}
+		acceptCh <- c
+	}()
+
+	client, err := l.Dial(opts...)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+
+	select {
+	case server = <-acceptCh:
+	case err := <-acceptErrCh:
+		t.Fatalf("Accept: %v", err)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for Accept")
+	}
+
+	return client, server
+}
+
+func TestPipeRoundTrip(t *testing.T) {
+	client, server := dial(t)
+	defer client.Close()
+	defer server.Close()
+
+	want := []byte("hello dtls")
+	if _, err := client.Write(want); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	got := make([]byte, len(want)+8)
+	n, err := server.Read(got)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if string(got[:n]) != string(want) {
+		t.Fatalf("got %q, want %q", got[:n], want)
+	}
+}
+
+func TestClosePropagatesToPeer(t *testing.T) {
+	client, server := dial(t)
+	defer server.Close()
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := server.Read(make([]byte, 1))
+		errCh <- err
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != io.EOF {
+			t.Fatalf("Read after peer Close() = %v, want io.EOF", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Read blocked after peer Close()")
+	}
+}
+
+func TestWriteDeadlineOnFullQueue(t *testing.T) {
+	client, server := dial(t)
+	defer client.Close()
+	defer server.Close()
+
+	// Fill the server's read queue (capacity 4) without ever draining it,
+	// then block on a 5th write until the deadline fires.
+	for i := 0; i < 4; i++ {
+		if _, err := client.Write([]byte("x")); err != nil {
+			t.Fatalf("Write %d: %v", i, err)
+		}
+	}
+
+	if err := client.SetWriteDeadline(time.Now().Add(50 * time.Millisecond)); err != nil {
+		t.Fatalf("SetWriteDeadline: %v", err)
+	}
+
+	_, err := client.Write([]byte("x"))
+	if !os.IsTimeout(err) {
+		t.Fatalf("Write past deadline = %v, want a timeout error", err)
+	}
+}
+
+func TestWithLossDropsPackets(t *testing.T) {
+	client, server := dial(t, WithLoss(1))
+	defer client.Close()
+	defer server.Close()
+
+	if _, err := client.Write([]byte("dropped")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	if err := server.SetReadDeadline(time.Now().Add(50 * time.Millisecond)); err != nil {
+		t.Fatalf("SetReadDeadline: %v", err)
+	}
+
+	_, err := server.Read(make([]byte, 16))
+	if !os.IsTimeout(err) {
+		t.Fatalf("Read after WithLoss(1) = %v, want a timeout error", err)
+	}
+}
+
+func TestCloseFlushesPendingReorder(t *testing.T) {
+	client, server := dial(t, WithReorder(1))
+	defer server.Close()
+
+	// With reorderProb 1, this Write is stashed rather than sent immediately.
+	if _, err := client.Write([]byte("stashed")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if err := server.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("SetReadDeadline: %v", err)
+	}
+
+	buf := make([]byte, 16)
+	n, err := server.Read(buf)
+	if err != nil {
+		t.Fatalf("Read after peer Close() = %v, want the flushed packet", err)
+	}
+	if string(buf[:n]) != "stashed" {
+		t.Fatalf("Read = %q, want %q", buf[:n], "stashed")
+	}
+}
+
+func TestWithDuplicationDeliversTwice(t *testing.T) {
+	client, server := dial(t, WithDuplication(1))
+	defer client.Close()
+	defer server.Close()
+
+	if _, err := client.Write([]byte("dup")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := server.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+			t.Fatalf("SetReadDeadline: %v", err)
+		}
+		buf := make([]byte, 16)
+		n, err := server.Read(buf)
+		if err != nil {
+			t.Fatalf("Read %d: %v", i, err)
+		}
+		if string(buf[:n]) != "dup" {
+			t.Fatalf("Read %d = %q, want %q", i, buf[:n], "dup")
+		}
+	}
+}
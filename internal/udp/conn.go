@@ -5,17 +5,96 @@ import (
 	"io"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-const receiveMTU = 8192
+const (
+	defaultReceiveMTU    = 8192
+	defaultAcceptBacklog = 128
+	defaultReadQueueLen  = 128
+)
+
 const closeRecheckDuration = 100 * time.Millisecond
 
 var errClosedListener = errors.New("udp: listener closed")
 
-// Listener augments a connection-oriented Listener over a UDP PacketConn
+// ListenConfig stores options for listening to an address.
+type ListenConfig struct {
+	// AcceptBacklog defines the maximum length of the queue of pending
+	// connections waiting to be accepted. A Conn that would exceed the
+	// backlog is silently dropped, the same way a TCP SYN would be
+	// dropped by an overloaded listen socket. Defaults to 128.
+	AcceptBacklog int
+
+	// ReadQueueLen defines the maximum number of packets that can be
+	// queued per Conn, waiting to be read by that Conn. A Conn that does
+	// not keep up with its inbound packets drops the newest-arriving
+	// packet (tail-drop) rather than stalling delivery to every other
+	// Conn. Defaults to 128.
+	ReadQueueLen int
+
+	// ReceiveMTU defines the size of the receive buffers used by the
+	// listener's read loop, and therefore the largest packet size that
+	// can be read without truncation. Defaults to 8192.
+	ReceiveMTU int
+}
+
+// Listen creates a new listener on a UDP socket bound to laddr, using the
+// given ListenConfig. It is a thin convenience wrapper around NewListener
+// for the common case of listening on a real UDP socket.
+func (lc ListenConfig) Listen(network string, laddr *net.UDPAddr) (*Listener, error) {
+	conn, err := net.ListenUDP(network, laddr)
+	if err != nil {
+		return nil, err
+	}
+
+	return lc.NewListener(conn), nil
+}
+
+// NewListener augments pc with the connection-oriented semantics of
+// Listener/Conn, using the given ListenConfig. Unlike Listen, pc need not
+// be a *net.UDPConn: any net.PacketConn works, which lets callers layer
+// this package over other datagram transports (e.g. a KCP session, a
+// pion/ice Conn, or a QUIC datagram channel) without forking it.
+func (lc ListenConfig) NewListener(pc net.PacketConn) *Listener {
+	backlog := lc.AcceptBacklog
+	if backlog <= 0 {
+		backlog = defaultAcceptBacklog
+	}
+
+	readQueueLen := lc.ReadQueueLen
+	if readQueueLen <= 0 {
+		readQueueLen = defaultReadQueueLen
+	}
+
+	receiveMTU := lc.ReceiveMTU
+	if receiveMTU <= 0 {
+		receiveMTU = defaultReceiveMTU
+	}
+
+	l := &Listener{
+		pConn:        pc,
+		accepting:    true,
+		acceptCh:     make(chan *Conn, backlog),
+		conns:        make(map[string]*Conn),
+		doneCh:       make(chan struct{}),
+		readQueueLen: readQueueLen,
+		pool: &sync.Pool{
+			New: func() interface{} {
+				return make([]byte, receiveMTU)
+			},
+		},
+	}
+
+	go l.readLoop()
+
+	return l
+}
+
+// Listener augments a connection-oriented Listener over a net.PacketConn
 type Listener struct {
-	pConn *net.UDPConn
+	pConn net.PacketConn
 
 	lock      sync.RWMutex
 	accepting bool
@@ -23,6 +102,9 @@ type Listener struct {
 	doneCh    chan struct{}
 	doneOnce  sync.Once
 
+	readQueueLen int
+	pool         *sync.Pool
+
 	conns map[string]*Conn
 }
 
@@ -80,23 +162,17 @@ func (l *Listener) Addr() net.Addr {
 	return l.pConn.LocalAddr()
 }
 
-// Listen creates a new listener
+// Listen creates a new listener on a UDP socket, using the default
+// ListenConfig.
 func Listen(network string, laddr *net.UDPAddr) (*Listener, error) {
-	conn, err := net.ListenUDP(network, laddr)
-	if err != nil {
-		return nil, err
-	}
-
-	l := &Listener{
-		pConn:    conn,
-		acceptCh: make(chan *Conn),
-		conns:    make(map[string]*Conn),
-		doneCh:   make(chan struct{}),
-	}
-
-	go l.readLoop()
+	return ListenConfig{}.Listen(network, laddr)
+}
 
-	return l, nil
+// NewListener augments pc with the connection-oriented semantics of
+// Listener/Conn, using the default ListenConfig. See
+// ListenConfig.NewListener.
+func NewListener(pc net.PacketConn) *Listener {
+	return ListenConfig{}.NewListener(pc)
 }
 
 // readLoop has to tasks:
@@ -104,79 +180,112 @@ func Listen(network string, laddr *net.UDPAddr) (*Listener, error) {
 //    It can therefore not be ended until all Conns are closed.
 // 2. Creating a new Conn when receiving from a new remote.
 func (l *Listener) readLoop() {
-	buf := make([]byte, receiveMTU)
-
-readLoop:
 	for {
+		buf := l.pool.Get().([]byte) //nolint:forcetypeassert
+
 		n, raddr, err := l.pConn.ReadFrom(buf)
 		if err != nil {
 			return
 		}
-		conn, err := l.getConn(raddr)
-		if err != nil {
+
+		conn, ok := l.getConn(raddr)
+		if !ok {
+			l.pool.Put(buf) //nolint:staticcheck
 			continue
 		}
+
 		select {
-		case cBuf := <-conn.readCh:
-			n = copy(cBuf, buf[:n])
-			conn.sizeCh <- n
-		case <-conn.doneCh:
-			continue readLoop
+		case conn.queue <- buf[:n]:
+		default:
+			// The Conn's read queue is full; it isn't keeping up, so this
+			// newly-arrived packet is tail-dropped rather than blocking
+			// (and thereby stalling) every other Conn.
+			atomic.AddUint64(&conn.drops, 1)
+			l.pool.Put(buf) //nolint:staticcheck
 		}
 	}
 }
 
-func (l *Listener) getConn(raddr net.Addr) (*Conn, error) {
+// getConn returns the Conn associated with raddr, creating (and accepting)
+// one if it doesn't already exist. If the accept backlog is full, or the
+// listener is no longer accepting new Conns, ok is false and the packet
+// that triggered the lookup should be dropped.
+func (l *Listener) getConn(raddr net.Addr) (conn *Conn, ok bool) {
 	l.lock.Lock()
 	defer l.lock.Unlock()
 
-	conn, ok := l.conns[raddr.String()]
-	if !ok {
-		if !l.accepting {
-			return nil, errClosedListener
-		}
-		conn = l.newConn(raddr)
+	if conn, ok = l.conns[raddr.String()]; ok {
+		return conn, true
+	}
+	if !l.accepting {
+		return nil, false
+	}
+
+	conn = l.newConn(raddr)
+	select {
+	case l.acceptCh <- conn:
 		l.conns[raddr.String()] = conn
-		l.acceptCh <- conn
+		return conn, true
+	default:
+		// Accept backlog is full; drop this SYN-equivalent.
+		return nil, false
 	}
-	return conn, nil
 }
 
-// Conn augments a connection-oriented connection over a UDP PacketConn
+// Conn augments a connection-oriented connection over a net.PacketConn
 type Conn struct {
 	listener *Listener
 
 	rAddr net.Addr
 
-	readCh chan []byte
-	sizeCh chan int
+	// queue holds packets handed off by the listener's readLoop, each in a
+	// receiveMTU-sized buffer taken from pool; Read copies out of the
+	// buffer and puts it back into pool.
+	queue chan []byte
+	pool  *sync.Pool
+	// drops counts packets discarded because queue was full.
+	drops uint64
 
 	lock     sync.RWMutex
 	doneCh   chan struct{}
 	doneOnce sync.Once
+
+	readDeadline  pipeDeadline
+	writeDeadline pipeDeadline
 }
 
 func (l *Listener) newConn(rAddr net.Addr) *Conn {
 	return &Conn{
-		listener: l,
-		rAddr:    rAddr,
-		readCh:   make(chan []byte),
-		sizeCh:   make(chan int),
-		doneCh:   make(chan struct{}),
+		listener:      l,
+		rAddr:         rAddr,
+		queue:         make(chan []byte, l.readQueueLen),
+		pool:          l.pool,
+		doneCh:        make(chan struct{}),
+		readDeadline:  makePipeDeadline(),
+		writeDeadline: makePipeDeadline(),
 	}
 }
 
 // Read
 func (c *Conn) Read(p []byte) (int, error) {
 	select {
-	case c.readCh <- p:
-		n := <-c.sizeCh
+	case buf := <-c.queue:
+		n := copy(p, buf)
+		c.pool.Put(buf[:cap(buf)]) //nolint:staticcheck
 		return n, nil
 	case <-c.doneCh:
 		return 0, io.EOF
+	case <-c.readDeadline.wait():
+		return 0, errTimeout
 	}
 }
 
+// Drops returns the number of packets dropped because this Conn's read
+// queue was full.
+func (c *Conn) Drops() uint64 {
+	return atomic.LoadUint64(&c.drops)
+}
+
 // Write writes len(p) bytes from p to the DTLS connection
 func (c *Conn) Write(p []byte) (n int, err error) {
 	c.lock.Lock()
@@ -187,7 +296,14 @@ func (c *Conn) Write(p []byte) (n int, err error) {
 		return 0, io.EOF
 	}
 
-	return l.pConn.WriteTo(p, c.rAddr)
+	select {
+	case <-c.writeDeadline.wait():
+		return 0, errTimeout
+	case <-c.doneCh:
+		return 0, io.EOF
+	default:
+		return l.pConn.WriteTo(p, c.rAddr)
+	}
 }
 
 // Close closes the conn and releases any Read calls
@@ -225,17 +341,24 @@ func (c *Conn) RemoteAddr() net.Addr {
 	return c.rAddr
 }
 
-// SetDeadline is a stub
+// SetDeadline sets the read and write deadlines associated with the Conn.
+// A zero value for t means Read and Write will not time out.
 func (c *Conn) SetDeadline(t time.Time) error {
+	c.readDeadline.set(t)
+	c.writeDeadline.set(t)
 	return nil
 }
 
-// SetReadDeadline is a stub
+// SetReadDeadline sets the deadline for future Read calls.
+// A zero value for t means Read will not time out.
 func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.readDeadline.set(t)
 	return nil
 }
 
-// SetWriteDeadline is a stub
+// SetWriteDeadline sets the deadline for future Write calls.
+// A zero value for t means Write will not time out.
 func (c *Conn) SetWriteDeadline(t time.Time) error {
+	c.writeDeadline.set(t)
 	return nil
 }
@@ -0,0 +1,86 @@
+package udp
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func mustListen(t *testing.T, lc ListenConfig) *Listener {
+	t.Helper()
+
+	l, err := lc.Listen("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	return l
+}
+
+// TestReadQueueOverflowDrops verifies that once a Conn's read queue fills,
+// further packets for that Conn are tail-dropped (and counted) instead of
+// blocking the listener's readLoop.
+func TestReadQueueOverflowDrops(t *testing.T) {
+	l := mustListen(t, ListenConfig{ReadQueueLen: 2})
+	defer l.Close(0) //nolint:errcheck
+
+	raddr := l.Addr().(*net.UDPAddr)
+	sender, err := net.DialUDP("udp", nil, raddr)
+	if err != nil {
+		t.Fatalf("DialUDP: %v", err)
+	}
+	defer sender.Close()
+
+	for i := 0; i < 5; i++ {
+		if _, err := sender.Write([]byte{byte(i)}); err != nil {
+			t.Fatalf("Write %d: %v", i, err)
+		}
+	}
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if conn.Drops() > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no packets were dropped from an overflowing read queue")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestAcceptBacklogDropsNewConns verifies that once the accept backlog is
+// full, packets from additional new remotes are dropped rather than
+// blocking the listener's readLoop indefinitely.
+func TestAcceptBacklogDropsNewConns(t *testing.T) {
+	l := mustListen(t, ListenConfig{AcceptBacklog: 1})
+	defer l.Close(0) //nolint:errcheck
+
+	raddr := l.Addr().(*net.UDPAddr)
+
+	for i := 0; i < 3; i++ {
+		sender, err := net.DialUDP("udp", nil, raddr)
+		if err != nil {
+			t.Fatalf("DialUDP %d: %v", i, err)
+		}
+		defer sender.Close()
+
+		if _, err := sender.Write([]byte{byte(i)}); err != nil {
+			t.Fatalf("Write %d: %v", i, err)
+		}
+	}
+
+	// The listener's readLoop must keep servicing the socket (and dropping
+	// the SYN-equivalents that don't fit in the backlog) instead of
+	// wedging, so a single Accept still succeeds.
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	conn.Close()
+}
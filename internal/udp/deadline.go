@@ -0,0 +1,85 @@
+package udp
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// errTimeout is returned from Read/Write when the associated deadline has
+// elapsed. It is os.ErrDeadlineExceeded, which already satisfies net.Error
+// with Timeout() == true, so callers can use errors.Is(err,
+// os.ErrDeadlineExceeded) the same way they would against a real
+// net.Conn's deadline error.
+var errTimeout = os.ErrDeadlineExceeded
+
+// pipeDeadline is an abstraction for handling timeouts on a rendezvous-style
+// connection that has no underlying OS-level deadline support of its own.
+// It is the same pattern used internally by net.Pipe.
+type pipeDeadline struct {
+	mu     sync.Mutex // guards timer and cancel
+	timer  *time.Timer
+	cancel chan struct{} // closed when deadline is exceeded
+}
+
+func makePipeDeadline() pipeDeadline {
+	return pipeDeadline{cancel: make(chan struct{})}
+}
+
+// set sets the point in time when the deadline will time out.
+// A timeout event is signaled by closing the channel returned by wait.
+// Once a timeout has occurred, the deadline can be refreshed by specifying
+// a t value in the future.
+//
+// A zero value for t prevents timeout.
+func (d *pipeDeadline) set(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.timer != nil && !d.timer.Stop() {
+		<-d.cancel // Wait for the timer callback to finish and close cancel
+	}
+	d.timer = nil
+
+	closed := isClosedChan(d.cancel)
+
+	if t.IsZero() {
+		// No deadline.
+		if closed {
+			d.cancel = make(chan struct{})
+		}
+		return
+	}
+
+	if dur := time.Until(t); dur > 0 {
+		// Deadline is in the future, arm a timer to cancel in dur.
+		if closed {
+			d.cancel = make(chan struct{})
+		}
+		d.timer = time.AfterFunc(dur, func() {
+			close(d.cancel)
+		})
+		return
+	}
+
+	// Deadline is in the past, cancel immediately.
+	if !closed {
+		close(d.cancel)
+	}
+}
+
+// wait returns a channel that is closed when the deadline is exceeded.
+func (d *pipeDeadline) wait() chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.cancel
+}
+
+func isClosedChan(c chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,64 @@
+package udp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPipeDeadlineExceeded(t *testing.T) {
+	d := makePipeDeadline()
+	d.set(time.Now().Add(10 * time.Millisecond))
+
+	select {
+	case <-d.wait():
+	case <-time.After(time.Second):
+		t.Fatal("wait() never closed after deadline elapsed")
+	}
+}
+
+func TestPipeDeadlineZeroClearsTimeout(t *testing.T) {
+	d := makePipeDeadline()
+	d.set(time.Now().Add(10 * time.Millisecond))
+	d.set(time.Time{})
+
+	select {
+	case <-d.wait():
+		t.Fatal("wait() closed after deadline was cleared")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestPipeDeadlineInPast(t *testing.T) {
+	d := makePipeDeadline()
+	d.set(time.Now().Add(-time.Second))
+
+	select {
+	case <-d.wait():
+	default:
+		t.Fatal("wait() not closed for a deadline already in the past")
+	}
+}
+
+func TestPipeDeadlineCanBeRearmed(t *testing.T) {
+	d := makePipeDeadline()
+	d.set(time.Now().Add(-time.Second))
+
+	select {
+	case <-d.wait():
+	default:
+		t.Fatal("wait() should be closed immediately after an expired set")
+	}
+
+	d.set(time.Now().Add(50 * time.Millisecond))
+	select {
+	case <-d.wait():
+		t.Fatal("wait() closed immediately after re-arming with a future deadline")
+	default:
+	}
+
+	select {
+	case <-d.wait():
+	case <-time.After(time.Second):
+		t.Fatal("wait() never closed after the re-armed deadline elapsed")
+	}
+}
@@ -3,7 +3,10 @@ package dtls
 import (
 	"crypto/elliptic"
 	"crypto/rand"
+	"math/big"
 
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/cloudflare/circl/dh/x448"
 	"golang.org/x/crypto/curve25519"
 )
 
@@ -17,17 +20,68 @@ type namedCurveKeypair struct {
 }
 
 const (
-	namedCurveP256   namedCurve = 0x0017 // 23
-	namedCurveP384   namedCurve = 0x0018 // 24
-	namedCurveP521   namedCurve = 0x0019 // 25
-	namedCurveX25519 namedCurve = 0x001d // 29
+	namedCurveSecp256k1 namedCurve = 0x0016 // 22
+	namedCurveP256      namedCurve = 0x0017 // 23
+	namedCurveP384      namedCurve = 0x0018 // 24
+	namedCurveP521      namedCurve = 0x0019 // 25
+	namedCurveX25519    namedCurve = 0x001d // 29
+	namedCurveX448      namedCurve = 0x001e // 30
+	namedCurveFFDHE2048 namedCurve = 0x0100 // 256
+	namedCurveFFDHE3072 namedCurve = 0x0101 // 257
+	namedCurveFFDHE4096 namedCurve = 0x0102 // 258
+	namedCurveFFDHE6144 namedCurve = 0x0103 // 259
+	namedCurveFFDHE8192 namedCurve = 0x0104 // 260
 )
 
 var namedCurves = map[namedCurve]bool{
-	namedCurveX25519: true,
-	namedCurveP256:   true,
-	namedCurveP384:   true,
-	namedCurveP521:   true,
+	namedCurveSecp256k1: true,
+	namedCurveX25519:    true,
+	namedCurveP256:      true,
+	namedCurveP384:      true,
+	namedCurveP521:      true,
+	namedCurveX448:      true,
+	namedCurveFFDHE2048: true,
+	namedCurveFFDHE3072: true,
+	namedCurveFFDHE4096: true,
+	namedCurveFFDHE6144: true,
+	namedCurveFFDHE8192: true,
+}
+
+// ellipticCurveForNamedCurve returns the crypto/elliptic curve backing the
+// given named curve, for the curves that are representable that way.
+func ellipticCurveForNamedCurve(c namedCurve) (elliptic.Curve, error) {
+	switch c {
+	case namedCurveSecp256k1:
+		return btcec.S256(), nil
+	case namedCurveP256:
+		return elliptic.P256(), nil
+	case namedCurveP384:
+		return elliptic.P384(), nil
+	case namedCurveP521:
+		return elliptic.P521(), nil
+	}
+	return nil, errInvalidNamedCurve
+}
+
+// ffdhePrimes holds the fixed RFC 7919 (https://tools.ietf.org/html/rfc7919)
+// safe primes for the "ffdhe" FFDHE groups. The generator for every group
+// defined by RFC 7919 is 2.
+var ffdhePrimes = map[namedCurve]*big.Int{
+	namedCurveFFDHE2048: mustParsePrime(ffdhe2048PrimeHex),
+	namedCurveFFDHE3072: mustParsePrime(ffdhe3072PrimeHex),
+	namedCurveFFDHE4096: mustParsePrime(ffdhe4096PrimeHex),
+	namedCurveFFDHE6144: mustParsePrime(ffdhe6144PrimeHex),
+	namedCurveFFDHE8192: mustParsePrime(ffdhe8192PrimeHex),
+}
+
+var ffdheGenerator = big.NewInt(2)
+
+func mustParsePrime(hexStr string) *big.Int {
+	p, ok := new(big.Int).SetString(hexStr, 16)
+	if !ok {
+		panic("dtls: invalid FFDHE prime")
+	}
+	return p
 }
 
 func generateKeypair(c namedCurve) (*namedCurveKeypair, error) {
@@ -43,27 +97,108 @@ func generateKeypair(c namedCurve) (*namedCurveKeypair, error) {
 
 		curve25519.ScalarBaseMult(&public, &private)
 		return &namedCurveKeypair{namedCurveX25519, public[:], private[:]}, nil
-	case namedCurveP256:
-		privateKey, x, y, err := elliptic.GenerateKey(elliptic.P256(), rand.Reader)
+	case namedCurveX448:
+		tmp := make([]byte, x448.Size)
+		if _, err := rand.Read(tmp); err != nil {
+			return nil, err
+		}
+
+		var public, private x448.Key
+		copy(private[:], tmp)
+
+		x448.KeyGen(&public, &private)
+		return &namedCurveKeypair{namedCurveX448, public[:], private[:]}, nil
+	case namedCurveSecp256k1, namedCurveP256, namedCurveP384, namedCurveP521:
+		curve, err := ellipticCurveForNamedCurve(c)
 		if err != nil {
 			return nil, err
 		}
 
-		return &namedCurveKeypair{namedCurveP256, elliptic.Marshal(elliptic.P256(), x, y), privateKey}, nil
-	case namedCurveP384:
-		privateKey, x, y, err := elliptic.GenerateKey(elliptic.P384(), rand.Reader)
+		privateKey, x, y, err := elliptic.GenerateKey(curve, rand.Reader)
 		if err != nil {
 			return nil, err
 		}
 
-		return &namedCurveKeypair{namedCurveP256, elliptic.Marshal(elliptic.P384(), x, y), privateKey}, nil
-	case namedCurveP521:
-		privateKey, x, y, err := elliptic.GenerateKey(elliptic.P521(), rand.Reader)
+		return &namedCurveKeypair{c, elliptic.Marshal(curve, x, y), privateKey}, nil
+	}
+
+	if prime, ok := ffdhePrimes[c]; ok {
+		private, err := rand.Int(rand.Reader, new(big.Int).Sub(prime, big.NewInt(3)))
 		if err != nil {
 			return nil, err
 		}
+		private.Add(private, big.NewInt(2)) // private in [2, prime-1)
 
-		return &namedCurveKeypair{namedCurveP256, elliptic.Marshal(elliptic.P521(), x, y), privateKey}, nil
+		public := new(big.Int).Exp(ffdheGenerator, private, prime)
+
+		primeLen := (prime.BitLen() + 7) / 8
+		return &namedCurveKeypair{c, fixedSizeBytes(public, primeLen), fixedSizeBytes(private, primeLen)}, nil
 	}
+
 	return nil, errInvalidNamedCurve
 }
+
+// keyExchange derives the pre-master secret for this keypair's curve, given
+// the peer's public key, abstracting over the ECDHE/X25519/X448/FFDHE
+// mechanics so callers have a single entry point regardless of curve.
+func (k *namedCurveKeypair) keyExchange(peerPublicKey []byte) (preMasterSecret []byte, err error) {
+	switch k.curve {
+	case namedCurveX25519:
+		return curve25519.X25519(k.privateKey, peerPublicKey)
+	case namedCurveX448:
+		var private, peer, shared x448.Key
+		copy(private[:], k.privateKey)
+		copy(peer[:], peerPublicKey)
+
+		if !x448.Shared(&shared, &private, &peer) {
+			return nil, errInvalidNamedCurve
+		}
+		return shared[:], nil
+	case namedCurveSecp256k1, namedCurveP256, namedCurveP384, namedCurveP521:
+		curve, err := ellipticCurveForNamedCurve(k.curve)
+		if err != nil {
+			return nil, err
+		}
+
+		x, y := elliptic.Unmarshal(curve, peerPublicKey)
+		if x == nil {
+			return nil, errInvalidNamedCurve
+		}
+
+		preMasterX, _ := curve.ScalarMult(x, y, k.privateKey)
+		return fixedSizeBytes(preMasterX, (curve.Params().BitSize+7)/8), nil
+	}
+
+	if prime, ok := ffdhePrimes[k.curve]; ok {
+		peerPublic := new(big.Int).SetBytes(peerPublicKey)
+		if !validFFDHEPublicValue(peerPublic, prime) {
+			return nil, errInvalidNamedCurve
+		}
+
+		private := new(big.Int).SetBytes(k.privateKey)
+
+		shared := new(big.Int).Exp(peerPublic, private, prime)
+		return fixedSizeBytes(shared, (prime.BitLen()+7)/8), nil
+	}
+
+	return nil, errInvalidNamedCurve
+}
+
+// validFFDHEPublicValue reports whether peerPublic is an acceptable FFDHE
+// public value for the given safe prime: strictly between 1 and prime-1, so
+// that a malicious or buggy peer cannot force a small-subgroup or otherwise
+// degenerate shared secret (e.g. by sending 0, 1, or prime-1).
+func validFFDHEPublicValue(peerPublic, prime *big.Int) bool {
+	lowerBound := big.NewInt(1)
+	upperBound := new(big.Int).Sub(prime, big.NewInt(1))
+	return peerPublic.Cmp(lowerBound) > 0 && peerPublic.Cmp(upperBound) < 0
+}
+
+// fixedSizeBytes encodes n as big-endian bytes left-padded with zeroes to
+// exactly size bytes, as required for DH/ECDH public values on the wire.
+func fixedSizeBytes(n *big.Int, size int) []byte {
+	out := make([]byte, size)
+	b := n.Bytes()
+	copy(out[size-len(b):], b)
+	return out
+}
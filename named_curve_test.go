@@ -0,0 +1,148 @@
+package dtls
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+// TestKeyExchangeAgreement verifies that two independently generated
+// keypairs for the same curve derive identical pre-master secrets from each
+// other's public keys, for secp256k1, X448 and each of the FFDHE groups.
+func TestKeyExchangeAgreement(t *testing.T) {
+	curves := []namedCurve{
+		namedCurveSecp256k1,
+		namedCurveX448,
+		namedCurveFFDHE2048,
+		namedCurveFFDHE3072,
+		namedCurveFFDHE4096,
+		namedCurveFFDHE6144,
+		namedCurveFFDHE8192,
+	}
+
+	for _, c := range curves {
+		c := c
+		t.Run(curveName(c), func(t *testing.T) {
+			alice, err := generateKeypair(c)
+			if err != nil {
+				t.Fatalf("generateKeypair(alice): %v", err)
+			}
+			bob, err := generateKeypair(c)
+			if err != nil {
+				t.Fatalf("generateKeypair(bob): %v", err)
+			}
+
+			aliceSecret, err := alice.keyExchange(bob.publicKey)
+			if err != nil {
+				t.Fatalf("alice.keyExchange: %v", err)
+			}
+			bobSecret, err := bob.keyExchange(alice.publicKey)
+			if err != nil {
+				t.Fatalf("bob.keyExchange: %v", err)
+			}
+
+			if !bytes.Equal(aliceSecret, bobSecret) {
+				t.Fatalf("pre-master secrets disagree: %x != %x", aliceSecret, bobSecret)
+			}
+		})
+	}
+}
+
+// TestGenerateKeypairCurveTagging is a regression test for P384 and P521
+// keypairs being generated with, and tagged with, their own curve constant
+// rather than being mistakenly generated against P256.
+func TestGenerateKeypairCurveTagging(t *testing.T) {
+	for _, c := range []namedCurve{namedCurveP384, namedCurveP521} {
+		c := c
+		t.Run(curveName(c), func(t *testing.T) {
+			kp, err := generateKeypair(c)
+			if err != nil {
+				t.Fatalf("generateKeypair: %v", err)
+			}
+
+			if kp.curve != c {
+				t.Fatalf("keypair tagged with curve %v, want %v", kp.curve, c)
+			}
+
+			curve, err := ellipticCurveForNamedCurve(c)
+			if err != nil {
+				t.Fatalf("ellipticCurveForNamedCurve: %v", err)
+			}
+			wantLen := 2*((curve.Params().BitSize+7)/8) + 1
+			if len(kp.publicKey) != wantLen {
+				t.Fatalf("public key length = %d, want %d (got P256-sized key?)", len(kp.publicKey), wantLen)
+			}
+		})
+	}
+}
+
+// TestFFDHEPeerPublicValueValidation verifies that degenerate FFDHE peer
+// public values (0, 1, and prime-1) are rejected rather than fed into Exp.
+func TestFFDHEPeerPublicValueValidation(t *testing.T) {
+	kp, err := generateKeypair(namedCurveFFDHE2048)
+	if err != nil {
+		t.Fatalf("generateKeypair: %v", err)
+	}
+	prime := ffdhePrimes[namedCurveFFDHE2048]
+	primeLen := (prime.BitLen() + 7) / 8
+
+	bad := []*big.Int{
+		big.NewInt(0),
+		big.NewInt(1),
+		new(big.Int).Sub(prime, big.NewInt(1)),
+	}
+
+	for _, peerPublic := range bad {
+		if _, err := kp.keyExchange(fixedSizeBytes(peerPublic, primeLen)); err != errInvalidNamedCurve {
+			t.Fatalf("keyExchange(%v) = %v, want errInvalidNamedCurve", peerPublic, err)
+		}
+	}
+}
+
+func TestFixedSizeBytes(t *testing.T) {
+	cases := []struct {
+		n    *big.Int
+		size int
+		want []byte
+	}{
+		{big.NewInt(0x01), 4, []byte{0x00, 0x00, 0x00, 0x01}},
+		{big.NewInt(0x0102), 2, []byte{0x01, 0x02}},
+		{big.NewInt(0x0102), 4, []byte{0x00, 0x00, 0x01, 0x02}},
+	}
+
+	for _, tc := range cases {
+		got := fixedSizeBytes(tc.n, tc.size)
+		if !bytes.Equal(got, tc.want) {
+			t.Fatalf("fixedSizeBytes(%v, %d) = %x, want %x", tc.n, tc.size, got, tc.want)
+		}
+	}
+}
+
+func curveName(c namedCurve) string {
+	switch c {
+	case namedCurveSecp256k1:
+		return "secp256k1"
+	case namedCurveP256:
+		return "P256"
+	case namedCurveP384:
+		return "P384"
+	case namedCurveP521:
+		return "P521"
+	case namedCurveX25519:
+		return "X25519"
+	case namedCurveX448:
+		return "X448"
+	case namedCurveFFDHE2048:
+		return "FFDHE2048"
+	case namedCurveFFDHE3072:
+		return "FFDHE3072"
+	case namedCurveFFDHE4096:
+		return "FFDHE4096"
+	case namedCurveFFDHE6144:
+		return "FFDHE6144"
+	case namedCurveFFDHE8192:
+		return "FFDHE8192"
+	default:
+		return "unknown"
+	}
+}